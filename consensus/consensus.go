@@ -18,6 +18,8 @@
 package consensus
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"time"
 
@@ -30,6 +32,14 @@ import (
 
 var (
 	SystemAddress = common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE")
+
+	// ErrUnexpectedWithdrawals は、withdrawals フォーク以前のブロックに withdrawals
+	// フィールドが含まれている場合に返されます。
+	ErrUnexpectedWithdrawals = errors.New("unexpected withdrawals")
+
+	// ErrMissingWithdrawals は、withdrawals フォーク以降のブロックに withdrawals
+	// フィールドが含まれていない場合に返されます。
+	ErrMissingWithdrawals = errors.New("missing withdrawals")
 )
 
 // ChainHeaderReader defines a small collection of methods needed to access the local
@@ -54,6 +64,23 @@ type ChainHeaderReader interface {
 	GetHighestVerifiedHeader() *types.Header
 }
 
+// PoSChainHeaderReader extends ChainHeaderReader with the finalized/safe
+// header accessors needed to enforce consensus-layer-driven rules (e.g.
+// no-reorg-past-finalized). Only chains running a PoS engine need to
+// implement it, so it is kept separate from the broadly-implemented
+// ChainHeaderReader.
+type PoSChainHeaderReader interface {
+	ChainHeaderReader
+
+	// GetFinalizedHeader retrieves the finalized header reported by the
+	// consensus layer, or nil if none has been reported yet.
+	GetFinalizedHeader() *types.Header
+
+	// GetSafeHeader retrieves the safe header reported by the consensus
+	// layer, or nil if none has been reported yet.
+	GetSafeHeader() *types.Header
+}
+
 // ChainReader defines a small collection of methods needed to access the local
 // blockchain during header and/or uncle verification.
 type ChainReader interface {
@@ -75,11 +102,20 @@ type Engine interface {
 	// VerifySeal メソッドで明示的に行うこともできます。
 	VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error
 
-	// VerifyHeaders は VerifyHeader と似ていますが、ヘッダのバッチを検証します。
-	// 同時進行で検証します。このメソッドは，操作を中断するための quit チャネルと， // 検証結果を取得するための results チャネルを返します。
-	// 非同期の検証結果を取得するための結果チャンネルを返します（順序は入力スライスの
-	// 非同期検証を取得する結果チャネルを返します（順序は入力スライスの順序です）。)
-	VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error)
+	// VerifyHeaderContext は VerifyHeader と同様にヘッダを検証しますが、追加で ctx を
+	// 受け取ります。ctx がキャンセルされると検証は打ち切られます。
+	VerifyHeaderContext(ctx context.Context, chain ChainHeaderReader, header *types.Header, seal bool) error
+
+	// VerifyHeadersContext はヘッダのバッチを並行して検証し、非同期の検証結果を返す
+	// 結果チャネルを返します。中断には ctx を用い、ctx がキャンセルされると、まだ検証中の
+	// ヘッダの検証は打ち切られます。結果は入力スライスの順序で返されます。
+	VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error
+
+	// VerifyRange は VerifyHeadersContext と同様にヘッダのバッチを検証しますが、呼び出し元が
+	// 信頼できるチェックポイントヘッダ（例：Parlia のスナップショット境界）を渡すことができます。
+	// これにより、中間のバリデータセット探索を省略でき、並行して動作するスナップ同期ワーカーが
+	// 1 エポックずつ遡ることなく既知の有効なアンカーに対して大きなヘッダ範囲を検証できます。
+	VerifyRange(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, checkpoints []*types.Header) error
 
 	// VerifyUncles は、与えられたブロックのアンクルが、与えられたエンジンのコンセンサス
 	// 与えられたエンジンのコンセンサスルールに準拠しているかを検証します。
@@ -94,16 +130,29 @@ type Engine interface {
 	//
 	// 注意：ブロックヘッダと状態データベースは、ファイナライズ時に発生したコンセンサスルールを反映するために更新される可能性があります。
 	// ブロックヘッダと状態データベースは、ファイナライズ時に発生するコンセンサスルール（例：ブロックリワード）を反映するために更新されるかもしれません。
+	//
+	// 注意：withdrawals は Shanghai 以降のブロックでのみ非 nil です。フォーク前のブロックでは
+	// 呼び出し元は nil を渡す必要があります。
 	Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction,
-		uncles []*types.Header, receipts *[]*types.Receipt, systemTxs *[]*types.Transaction, usedGas *uint64) error
+		uncles []*types.Header, receipts *[]*types.Receipt, systemTxs *[]*types.Transaction, usedGas *uint64,
+		withdrawals []*types.Withdrawal) error
 
 	// FinalizeAndAssemble は、トランザクション後の状態の変更 (例：ブロック
 	// 報酬など）を実行し、最終ブロックを組み立てます。
 	//
 	// 注意：ブロックヘッダと状態データベースは、最終的に発生したコンセンサスルールを反映するために更新されるかもしれません。
 	// ブロックヘッダと状態データベースが更新され、最終処理で発生するコンセンサスルールが反映されるかもしれません。
+	//
+	// 注意：withdrawals は Shanghai 以降のブロックでのみ非 nil です。フォーク前のブロックでは
+	// 呼び出し元は nil を渡す必要があります。
 	FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
-		uncles []*types.Header, receipts []*types.Receipt) (*types.Block, []*types.Receipt, error)
+		uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, []*types.Receipt, error)
+
+	// VerifyWithdrawals は、ヘッダに付随する withdrawals が、フォークスケジュールおよび
+	// withdrawalsRoot と整合しているかどうかを検証します。withdrawals フォーク前のヘッダに
+	// withdrawals が含まれる場合は ErrUnexpectedWithdrawals を、フォーク後のヘッダに
+	// withdrawals が含まれない場合は ErrMissingWithdrawals を返します。
+	VerifyWithdrawals(chain ChainHeaderReader, header *types.Header, withdrawals []*types.Withdrawal) error
 
 	// Seal は、与えられた入力ブロックに対して新しいシーリング要求を生成し、その結果を与えられたチャネルにプッシュします。
 	// その結果を与えられたチャネルにプッシュします。
@@ -137,6 +186,47 @@ type PoW interface {
 	Hashrate() float64
 }
 
+// PoS is a consensus engine whose canonical chain is driven by an external
+// consensus layer rather than by local difficulty/stake comparisons, e.g. a
+// fast-finality BLS-based finalization gadget.
+type PoS interface {
+	Engine
+
+	// SetFinalized records the header the consensus layer has marked finalized.
+	SetFinalized(header *types.Header)
+
+	// SetSafe records the header the consensus layer has marked safe.
+	SetSafe(header *types.Header)
+
+	// CurrentFinalized returns the most recently recorded finalized header.
+	CurrentFinalized() *types.Header
+
+	// CurrentSafe returns the most recently recorded safe header.
+	CurrentSafe() *types.Header
+
+	// ForkChoiceUpdate notifies the engine of a new head, safe and finalized
+	// block as reported by the consensus layer.
+	ForkChoiceUpdate(head, safe, finalized common.Hash) error
+
+	// NewPayload validates a block delivered by the consensus layer before it
+	// is imported into the local chain. chain is typed as PoSChainHeaderReader,
+	// not the plain ChainHeaderReader, so the engine can enforce
+	// no-reorg-past-finalized against chain.GetFinalizedHeader() directly
+	// instead of type-asserting its way to it.
+	NewPayload(chain PoSChainHeaderReader, block *types.Block) error
+}
+
+// SystemCaller is implemented by callers that can execute messages against
+// system contracts on behalf of a consensus engine, either as a plain call
+// (no state transition persisted beyond the invocation) or as a delegated
+// call that runs with the caller's own context. It lets stateless verifiers,
+// tracing tools and alternative execution backends supply system-contract
+// access without the engine ever touching a *state.StateDB.
+type SystemCaller interface {
+	Call(contract common.Address, data []byte) ([]byte, error)
+	Delegate(contract common.Address, data []byte) ([]byte, error)
+}
+
 type PoSA interface {
 	Engine
 
@@ -145,4 +235,18 @@ type PoSA interface {
 	EnoughDistance(chain ChainReader, header *types.Header) bool
 	IsLocalBlock(header *types.Header) bool
 	AllowLightProcess(chain ChainReader, currentHeader *types.Header) bool
+
+	// InitializeConsensus は、ブロック処理の開始時に呼び出され、ジェネシスやエポック境界での
+	// バリデータセットのローテーション、スラッシュ処理などのシステムコントラクトフックを実行します。
+	// システムコントラクトの呼び出しはすべて caller 経由で行われるため、*state.StateDB を
+	// 持たないステートレス呼び出し元からも利用できます。
+	InitializeConsensus(chain ChainHeaderReader, header *types.Header, caller SystemCaller) error
+
+	// FinalizeWithCallback は Finalize と同等のコンセンサス処理を行いますが、システムコントラクト
+	// 呼び出しを直接の *state.StateDB アクセスではなく caller 経由で行います。これにより、
+	// ステートレス検証器やトレーシングツール、代替実行バックエンドから core/vm に依存せずに
+	// システムコントラクト呼び出しを再利用できます。
+	FinalizeWithCallback(chain ChainHeaderReader, header *types.Header, txs *[]*types.Transaction,
+		uncles []*types.Header, receipts *[]*types.Receipt, systemTxs *[]*types.Transaction, usedGas *uint64,
+		withdrawals []*types.Withdrawal, caller SystemCaller) error
 }